@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bearerChallenge is a parsed WWW-Authenticate: Bearer ... challenge.
+type bearerChallenge struct {
+	Realm   string
+	Service string
+	Scope   string
+}
+
+// tokenCacheKey identifies a cached token by the host it was issued for and
+// the repository it was issued for; a single registry can hand out
+// different tokens for different repository scopes.
+type tokenCacheKey struct {
+	host       string
+	repository string
+}
+
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// BearerTransport is an http.RoundTripper that transparently handles the
+// distribution registry's Bearer token challenge: on a 401 response
+// carrying a WWW-Authenticate: Bearer header, it exchanges the challenge's
+// realm/service/scope for a token using the configured credentials, caches
+// the token per (host, repository), and retries the original request with
+// an Authorization: Bearer header attached.
+type BearerTransport struct {
+	// Base is the underlying transport used to make requests. Defaults to
+	// http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Username and Password are sent as HTTP basic auth when exchanging a
+	// challenge for a token. Leave both empty for anonymous token requests.
+	Username string
+	Password string
+
+	mu     sync.Mutex
+	tokens map[tokenCacheKey]cachedToken
+}
+
+// UseBearerAuth wraps r.Client.Transport in a BearerTransport configured
+// with username/password, so DownloadLayer, HasLayer, UploadLayer, and
+// manifest calls all handle WWW-Authenticate challenges transparently
+// instead of requiring callers to manage tokens themselves.
+func (r *Registry) UseBearerAuth(username, password string) {
+	r.Client.Transport = &BearerTransport{
+		Base:     r.Client.Transport,
+		Username: username,
+		Password: password,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *BearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	key := tokenCacheKey{host: req.URL.Host, repository: repositoryFromPath(req.URL.Path)}
+	if token, ok := t.cachedTokenFor(key); ok {
+		// req has not been sent yet, so its body is used as is.
+		authed := req.Clone(req.Context())
+		authed.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req = authed
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge, ok := parseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	token, err := t.fetchToken(req, challenge, key)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := cloneRequestWithAuth(req, token)
+	if err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(retry)
+}
+
+// repositoryFromPath extracts the repository name from a distribution API
+// request path such as "/v2/foo/bar/blobs/<digest>" or
+// "/v2/foo/bar/manifests/<ref>", so RoundTrip can guess the right cache key
+// for a request before any challenge has been seen on it. It returns "" for
+// paths it doesn't recognize, so all such paths on a host share one cache
+// entry; a token cached there is tried first, and a 401 challenge on it
+// fetches a fresh one for the real scope.
+func repositoryFromPath(path string) string {
+	rest := strings.TrimPrefix(path, "/v2/")
+	if rest == path {
+		return ""
+	}
+	for _, sep := range []string{"/blobs/", "/manifests/"} {
+		if idx := strings.Index(rest, sep); idx >= 0 {
+			return rest[:idx]
+		}
+	}
+	return ""
+}
+
+func (t *BearerTransport) cachedTokenFor(key tokenCacheKey) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cached, ok := t.tokens[key]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+func (t *BearerTransport) fetchToken(origReq *http.Request, challenge bearerChallenge, key tokenCacheKey) (string, error) {
+	realmURL, err := url.Parse(challenge.Realm)
+	if err != nil {
+		return "", err
+	}
+	q := realmURL.Query()
+	if challenge.Service != "" {
+		q.Set("service", challenge.Service)
+	}
+	if challenge.Scope != "" {
+		q.Set("scope", challenge.Scope)
+	}
+	realmURL.RawQuery = q.Encode()
+
+	tokenReq, err := http.NewRequestWithContext(origReq.Context(), "GET", realmURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if t.Username != "" || t.Password != "" {
+		tokenReq.SetBasicAuth(t.Username, t.Password)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(tokenReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &httpStatusError{Response: resp}
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	expiresIn := body.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 60
+	}
+
+	t.mu.Lock()
+	if t.tokens == nil {
+		t.tokens = map[tokenCacheKey]cachedToken{}
+	}
+	t.tokens[key] = cachedToken{token: token, expiresAt: time.Now().Add(time.Duration(expiresIn) * time.Second)}
+	t.mu.Unlock()
+
+	return token, nil
+}
+
+// cloneRequestWithAuth clones req and attaches an Authorization header. If
+// req had a body, the original has already been drained by a prior
+// RoundTrip, so the clone's body is reset from GetBody rather than reused.
+// A body with no GetBody can't be safely replayed, so that case is an
+// error rather than a silent truncated retry.
+func cloneRequestWithAuth(req *http.Request, token string) (*http.Request, error) {
+	if req.Body != nil && req.GetBody == nil {
+		return nil, fmt.Errorf("registry: cannot retry request to %s after 401: body is not replayable", req.URL)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+
+	return clone, nil
+}
+
+// parseBearerChallenge parses a WWW-Authenticate header of the form
+// `Bearer realm="...",service="...",scope="..."` per RFC 7235, tokenizing
+// the scheme and the comma-separated key="quoted" or key=token parameters.
+// It returns ok=false if header is not a Bearer challenge.
+func parseBearerChallenge(header string) (bearerChallenge, bool) {
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(strings.TrimSpace(scheme), "Bearer") {
+		return bearerChallenge{}, false
+	}
+
+	var challenge bearerChallenge
+	for _, param := range splitChallengeParams(params) {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+
+		switch strings.ToLower(key) {
+		case "realm":
+			challenge.Realm = value
+		case "service":
+			challenge.Service = value
+		case "scope":
+			challenge.Scope = value
+		}
+	}
+
+	return challenge, challenge.Realm != ""
+}
+
+// splitChallengeParams splits a comma-separated list of key=value or
+// key="value" pairs, respecting commas inside quoted values.
+func splitChallengeParams(s string) []string {
+	var params []string
+	var inQuotes bool
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	params = append(params, s[start:])
+	return params
+}
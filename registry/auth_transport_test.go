@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseBearerChallenge(t *testing.T) {
+	header := `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:foo/bar:pull,push"`
+
+	challenge, ok := parseBearerChallenge(header)
+	if !ok {
+		t.Fatalf("parseBearerChallenge(%q) returned ok=false", header)
+	}
+	if challenge.Realm != "https://auth.example.com/token" {
+		t.Errorf("Realm = %q, want %q", challenge.Realm, "https://auth.example.com/token")
+	}
+	if challenge.Service != "registry.example.com" {
+		t.Errorf("Service = %q, want %q", challenge.Service, "registry.example.com")
+	}
+	if challenge.Scope != "repository:foo/bar:pull,push" {
+		t.Errorf("Scope = %q, want %q", challenge.Scope, "repository:foo/bar:pull,push")
+	}
+}
+
+func TestParseBearerChallengeRejectsOtherSchemes(t *testing.T) {
+	if _, ok := parseBearerChallenge(`Basic realm="registry"`); ok {
+		t.Fatal("expected ok=false for a non-Bearer challenge")
+	}
+}
+
+// fakeBearerRegistry simulates a registry that challenges every
+// unauthenticated blob request with a Bearer scope for that request's own
+// repository, so a token fetched for one repository must not be handed
+// back for another.
+type fakeBearerRegistry struct {
+	serverURL     string
+	tokenRequests int
+}
+
+func newFakeBearerRegistryServer(fake *fakeBearerRegistry) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		fake.tokenRequests++
+		fmt.Fprintf(w, `{"token":"tok-%s"}`, r.URL.Query().Get("scope"))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		wantScope := fmt.Sprintf("repository:%s:pull", repositoryFromPath(r.URL.Path))
+		if r.Header.Get("Authorization") == "Bearer tok-"+wantScope {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Bearer realm="%s/token",service="test",scope="%s"`, fake.serverURL, wantScope))
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	server := httptest.NewServer(mux)
+	fake.serverURL = server.URL
+	return server
+}
+
+func TestBearerTransportCachesTokensPerRepository(t *testing.T) {
+	fake := &fakeBearerRegistry{}
+	server := newFakeBearerRegistryServer(fake)
+	defer server.Close()
+
+	client := &http.Client{Transport: &BearerTransport{}}
+
+	get := func(path string) {
+		t.Helper()
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	get("/v2/foo/bar/blobs/sha256:aaa")
+	get("/v2/foo/bar/blobs/sha256:bbb")
+	if fake.tokenRequests != 1 {
+		t.Errorf("tokenRequests = %d, want 1 (second request to the same repository should reuse the cached token)", fake.tokenRequests)
+	}
+
+	get("/v2/other/repo/blobs/sha256:ccc")
+	if fake.tokenRequests != 2 {
+		t.Errorf("tokenRequests = %d, want 2 (a different repository must not reuse foo/bar's cached token)", fake.tokenRequests)
+	}
+}
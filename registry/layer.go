@@ -3,9 +3,11 @@ package registry
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"fmt"
 
@@ -48,7 +50,7 @@ func (r *Registry) GetConfig(ctx context.Context, repository string, configDiges
 
 // UploadLayer uploads a specific layer by digest for a repository.
 func (r *Registry) UploadLayer(ctx context.Context, repository string, digest reference.Reference, content io.Reader) error {
-	uploadURL, token, err := r.initiateUpload(ctx, repository)
+	uploadURL, err := r.initiateUpload(ctx, repository)
 	if err != nil {
 		return err
 	}
@@ -63,7 +65,6 @@ func (r *Registry) UploadLayer(ctx context.Context, repository string, digest re
 		return err
 	}
 	upload.Header.Set("Content-Type", "application/octet-stream")
-	upload.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	_, err = r.Client.Do(upload.WithContext(ctx))
 	return err
@@ -99,26 +100,122 @@ func (r *Registry) HasLayer(ctx context.Context, repository string, digest diges
 	return false, err
 }
 
-func (r *Registry) initiateUpload(ctx context.Context, repository string) (*url.URL, string, error) {
+// BlobDescriptor is the metadata a registry reports for a blob via a HEAD
+// request, without requiring the blob itself to be downloaded.
+type BlobDescriptor struct {
+	Digest    digest.Digest
+	Size      int64
+	MediaType string
+}
+
+// ErrBlobUnknown is returned by StatLayer when the registry has no blob
+// matching the requested digest.
+var ErrBlobUnknown = errors.New("registry: blob unknown")
+
+// StatLayer returns size, media type, and content digest for a blob via a
+// single HEAD request, without downloading it. It returns ErrBlobUnknown if
+// the registry responds 404.
+func (r *Registry) StatLayer(ctx context.Context, repository string, digest digest.Digest) (*BlobDescriptor, error) {
+	statURL := r.url("/v2/%s/blobs/%s", repository, digest)
+	r.Logf("registry.layer.stat url=%s repository=%s digest=%s", statURL, repository, digest)
+
+	req, err := http.NewRequest("HEAD", statURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlobUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{Response: resp}
+	}
+
+	contentDigest := blobDigestFromHeader(resp.Header.Get("Docker-Content-Digest"))
+	if contentDigest == "" {
+		contentDigest = digest
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobDescriptor{
+		Digest:    contentDigest,
+		Size:      size,
+		MediaType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// blobDigestFromHeader converts a Docker-Content-Digest header value to a
+// digest.Digest, defined at package scope so StatLayer's digest parameter
+// can't shadow the digest package.
+func blobDigestFromHeader(header string) digest.Digest {
+	return digest.Digest(header)
+}
+
+// MountLayer attempts to mount a blob already present in srcRepository into
+// destRepository without re-uploading it, via POST
+// /v2/<destRepository>/blobs/uploads/?mount=<digest>&from=<srcRepository>.
+// A 201 response means the registry performed the mount (mounted=true). A
+// 202 response means the registry declined the mount and started a normal
+// upload session instead; callers should fall back to UploadLayer in that
+// case.
+func (r *Registry) MountLayer(ctx context.Context, destRepository, srcRepository string, digest digest.Digest) (bool, error) {
+	mountURL := r.url("/v2/%s/blobs/uploads/", destRepository)
+	q := url.Values{}
+	q.Set("mount", digest.String())
+	q.Set("from", srcRepository)
+	mountURL = fmt.Sprintf("%s?%s", mountURL, q.Encode())
+
+	r.Logf("registry.layer.mount url=%s destRepository=%s srcRepository=%s digest=%s", mountURL, destRepository, srcRepository, digest)
+
+	req, err := http.NewRequest("POST", mountURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusAccepted:
+		return false, nil
+	default:
+		return false, &httpStatusError{Response: resp}
+	}
+}
+
+// initiateUpload starts a new blob upload session and returns its upload
+// URL. It no longer reads a token off this response; see
+// Registry.UseBearerAuth for WWW-Authenticate handling.
+func (r *Registry) initiateUpload(ctx context.Context, repository string) (*url.URL, error) {
 	initiateURL := r.url("/v2/%s/blobs/uploads/", repository)
 	r.Logf("registry.layer.initiate-upload url=%s repository=%s", initiateURL, repository)
 
 	req, err := http.NewRequest("POST", initiateURL, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
 	resp, err := r.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	token := resp.Header.Get("Request-Token")
 	defer resp.Body.Close()
 
 	location := resp.Header.Get("Location")
-	locationURL, err := url.Parse(location)
-	if err != nil {
-		return nil, token, err
-	}
-	return locationURL, token, nil
+	return url.Parse(location)
 }
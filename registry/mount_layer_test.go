@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestMountLayer(t *testing.T) {
+	dgst := digest.FromString("layer contents")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/dest/repo/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+		if got := r.URL.Query().Get("mount"); got != dgst.String() {
+			t.Fatalf("mount = %q, want %q", got, dgst.String())
+		}
+		if got := r.URL.Query().Get("from"); got != "src/repo" {
+			t.Fatalf("from = %q, want %q", got, "src/repo")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	mounted, err := r.MountLayer(context.Background(), "dest/repo", "src/repo", dgst)
+	if err != nil {
+		t.Fatalf("MountLayer: %v", err)
+	}
+	if !mounted {
+		t.Fatal("expected mounted=true for a 201 response")
+	}
+}
+
+func TestMountLayerDeclined(t *testing.T) {
+	dgst := digest.FromString("layer contents")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/dest/repo/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	mounted, err := r.MountLayer(context.Background(), "dest/repo", "src/repo", dgst)
+	if err != nil {
+		t.Fatalf("MountLayer: %v", err)
+	}
+	if mounted {
+		t.Fatal("expected mounted=false for a 202 response")
+	}
+}
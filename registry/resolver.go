@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/distribution/distribution/v3/reference"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// manifestMediaTypes is the set of manifest and index media types Resolve
+// and Fetcher accept, covering current OCI and Docker formats plus legacy
+// schema1.
+var manifestMediaTypes = []string{
+	ocispec.MediaTypeImageManifest,
+	ocispec.MediaTypeImageIndex,
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v1+prettyjws",
+}
+
+// Resolver resolves a full image reference against a Registry into an OCI
+// descriptor, and hands back a Fetcher/Pusher bound to its repository.
+type Resolver struct {
+	Registry *Registry
+}
+
+// NewResolver returns a Resolver backed by r.
+func NewResolver(r *Registry) *Resolver {
+	return &Resolver{Registry: r}
+}
+
+// Resolve parses ref (e.g. "registry.example.com/foo/bar:tag" or
+// "...@sha256:...") and performs a HEAD against the manifest endpoint to
+// determine its digest, size, and media type. It returns an error if ref
+// names a host other than the one res.Registry is configured for. The
+// returned string is the bare repository (e.g. "foo/bar"), matching what
+// Fetcher and Pusher expect as their repository argument.
+func (res *Resolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+
+	registryURL, err := url.Parse(res.Registry.url("/"))
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	if host := reference.Domain(named); host != registryURL.Host {
+		return "", ocispec.Descriptor{}, fmt.Errorf("registry: reference host %q does not match resolver's registry %q", host, registryURL.Host)
+	}
+
+	repository := reference.Path(named)
+	manifestURL := res.Registry.url("/v2/%s/manifests/%s", repository, referenceObject(named))
+
+	req, err := http.NewRequest("HEAD", manifestURL, nil)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	for _, mt := range manifestMediaTypes {
+		req.Header.Add("Accept", mt)
+	}
+
+	res.Registry.Logf("registry.resolver.resolve url=%s repository=%s", manifestURL, repository)
+
+	resp, err := res.Registry.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", ocispec.Descriptor{}, &httpStatusError{Response: resp}
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+
+	return repository, ocispec.Descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    digest.Digest(resp.Header.Get("Docker-Content-Digest")),
+		Size:      size,
+	}, nil
+}
+
+// Fetcher returns a Fetcher bound to repository for retrieving the blobs
+// and manifests discovered while walking a resolved reference's graph.
+func (res *Resolver) Fetcher(ctx context.Context, repository string) (Fetcher, error) {
+	return &repositoryFetcher{registry: res.Registry, repository: repository}, nil
+}
+
+// Pusher returns a Pusher bound to repository.
+func (res *Resolver) Pusher(ctx context.Context, repository string) (Pusher, error) {
+	return &repositoryPusher{registry: res.Registry, repository: repository}, nil
+}
+
+// Fetcher retrieves the content addressed by an OCI descriptor.
+type Fetcher interface {
+	Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error)
+}
+
+// Pusher uploads content addressed by an OCI descriptor.
+type Pusher interface {
+	Push(ctx context.Context, desc ocispec.Descriptor, content io.Reader) error
+}
+
+// repositoryFetcher implements Fetcher against a single repository.
+type repositoryFetcher struct {
+	registry   *Registry
+	repository string
+}
+
+func (f *repositoryFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	if isManifestMediaType(desc.MediaType) {
+		return f.fetchManifest(ctx, desc)
+	}
+	return f.registry.DownloadLayerVerified(ctx, f.repository, desc.Digest)
+}
+
+func (f *repositoryFetcher) fetchManifest(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	manifestURL := f.registry.url("/v2/%s/manifests/%s", f.repository, desc.Digest)
+	f.registry.Logf("registry.resolver.fetch-manifest url=%s repository=%s digest=%s", manifestURL, f.repository, desc.Digest)
+
+	req, err := http.NewRequest("GET", manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", desc.MediaType)
+
+	resp, err := f.registry.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &httpStatusError{Response: resp}
+	}
+	return resp.Body, nil
+}
+
+// repositoryPusher implements Pusher against a single repository.
+type repositoryPusher struct {
+	registry   *Registry
+	repository string
+}
+
+func (p *repositoryPusher) Push(ctx context.Context, desc ocispec.Descriptor, content io.Reader) error {
+	if isManifestMediaType(desc.MediaType) {
+		return p.pushManifest(ctx, desc, content)
+	}
+	return p.registry.UploadLayer(ctx, p.repository, desc.Digest, content)
+}
+
+func (p *repositoryPusher) pushManifest(ctx context.Context, desc ocispec.Descriptor, content io.Reader) error {
+	manifestURL := p.registry.url("/v2/%s/manifests/%s", p.repository, desc.Digest)
+	p.registry.Logf("registry.resolver.push-manifest url=%s repository=%s digest=%s", manifestURL, p.repository, desc.Digest)
+
+	req, err := http.NewRequest("PUT", manifestURL, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", desc.MediaType)
+
+	_, err = p.registry.Client.Do(req.WithContext(ctx))
+	return err
+}
+
+// isManifestMediaType reports whether mediaType is one of the manifest or
+// index formats Resolve accepts, as opposed to a blob.
+func isManifestMediaType(mediaType string) bool {
+	for _, mt := range manifestMediaTypes {
+		if mt == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceObject returns the tag or digest object of a parsed
+// reference.Named suitable for use in a manifest URL, defaulting to
+// "latest" if neither a tag nor digest is present.
+func referenceObject(named reference.Named) string {
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String()
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		return tagged.Tag()
+	}
+	return "latest"
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestResolverResolveAndFetch(t *testing.T) {
+	const manifest = `{"schemaVersion":2}`
+	const manifestDigest = "sha256:deadbeef"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/foo/bar/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", manifestDigest)
+		w.Header().Set("Content-Length", "19")
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			w.Write([]byte(manifest))
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+	res := NewResolver(r)
+
+	ref := strings.TrimPrefix(server.URL, "http://") + "/foo/bar:latest"
+	repository, desc, err := res.Resolve(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if repository != "foo/bar" {
+		t.Fatalf("repository = %q, want %q", repository, "foo/bar")
+	}
+	if desc.Digest.String() != manifestDigest {
+		t.Fatalf("desc.Digest = %q, want %q", desc.Digest, manifestDigest)
+	}
+
+	fetcher, err := res.Fetcher(context.Background(), repository)
+	if err != nil {
+		t.Fatalf("Fetcher: %v", err)
+	}
+
+	body, err := fetcher.Fetch(context.Background(), desc)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	defer body.Close()
+
+	got := make([]byte, len(manifest))
+	if _, err := body.Read(got); err != nil {
+		t.Fatalf("reading fetched manifest: %v", err)
+	}
+	if string(got) != manifest {
+		t.Fatalf("fetched manifest = %q, want %q", got, manifest)
+	}
+}
+
+func TestResolverResolveRejectsWrongHost(t *testing.T) {
+	server := httptest.NewServer(http.NewServeMux())
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+	res := NewResolver(r)
+
+	_, _, err := res.Resolve(context.Background(), "registry.example.com/foo/bar:latest")
+	if err == nil {
+		t.Fatal("expected an error resolving a reference for a different host")
+	}
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestStatLayer(t *testing.T) {
+	dgst := digest.FromString("layer contents")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/"+dgst.String(), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Header().Set("Content-Length", "14")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	desc, err := r.StatLayer(context.Background(), "test", dgst)
+	if err != nil {
+		t.Fatalf("StatLayer: %v", err)
+	}
+	if desc.Digest != dgst {
+		t.Errorf("Digest = %q, want %q", desc.Digest, dgst)
+	}
+	if desc.Size != 14 {
+		t.Errorf("Size = %d, want 14", desc.Size)
+	}
+	if desc.MediaType != "application/octet-stream" {
+		t.Errorf("MediaType = %q, want %q", desc.MediaType, "application/octet-stream")
+	}
+}
+
+func TestStatLayerUnknown(t *testing.T) {
+	dgst := digest.FromString("layer contents")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/"+dgst.String(), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	_, err := r.StatLayer(context.Background(), "test", dgst)
+	if !errors.Is(err, ErrBlobUnknown) {
+		t.Fatalf("err = %v, want ErrBlobUnknown", err)
+	}
+}
@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// defaultChunkSize is the chunk size used by UploadLayerChunked when the
+// caller does not request a specific size.
+const defaultChunkSize = 10 * 1024 * 1024 // 10MiB
+
+// UploadSession tracks the upload URL and acknowledged byte offset of an
+// in-progress chunked blob upload.
+type UploadSession struct {
+	URL    *url.URL
+	Offset int64
+}
+
+// UploadLayerChunked uploads a layer to a repository in fixed-size chunks
+// via PATCH, finalizing with a digest-only PUT. chunkSize <= 0 falls back
+// to defaultChunkSize. Once the upload session has been opened, it is
+// returned even on error, so callers can pass session.URL and
+// session.Offset to ResumeUpload after a 416 or network error; if opening
+// the session fails, the returned session is nil.
+func (r *Registry) UploadLayerChunked(ctx context.Context, repository string, dgst digest.Digest, content io.Reader, chunkSize int64) (*UploadSession, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	uploadURL, err := r.initiateUpload(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &UploadSession{URL: uploadURL}
+	if err := r.uploadChunksAndFinalize(ctx, repository, dgst, session, content, chunkSize); err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// ResumeUpload continues a chunked upload from offset against uploadURL.
+// content must yield only the bytes starting at offset.
+func (r *Registry) ResumeUpload(ctx context.Context, uploadURL string, offset int64, dgst digest.Digest, content io.Reader) (*UploadSession, error) {
+	parsed, err := url.Parse(uploadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &UploadSession{URL: parsed, Offset: offset}
+	if err := r.uploadChunksAndFinalize(ctx, "", dgst, session, content, defaultChunkSize); err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+// uploadChunksAndFinalize PATCHes content to the upload session in
+// chunkSize pieces and, once content is exhausted, PUTs the digest to
+// finalize the upload.
+func (r *Registry) uploadChunksAndFinalize(ctx context.Context, repository string, dgst digest.Digest, session *UploadSession, content io.Reader, chunkSize int64) error {
+	if err := r.uploadChunks(ctx, repository, session, content, chunkSize); err != nil {
+		return err
+	}
+
+	return r.finalizeUpload(ctx, repository, dgst, session)
+}
+
+// uploadChunks PATCHes content to the upload session in chunkSize pieces,
+// without finalizing the upload. Callers that don't know the digest until
+// content is exhausted (UploadLayerStream) finalize separately.
+func (r *Registry) uploadChunks(ctx context.Context, repository string, session *UploadSession, content io.Reader, chunkSize int64) error {
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(content, buf)
+		if n > 0 {
+			if err := r.uploadChunk(ctx, repository, session, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	return nil
+}
+
+// uploadChunk PATCHes a single chunk of content to the session's upload
+// URL and advances the session's URL and offset from the response.
+func (r *Registry) uploadChunk(ctx context.Context, repository string, session *UploadSession, chunk []byte) error {
+	start := session.Offset
+	end := start + int64(len(chunk)) - 1
+
+	r.Logf("registry.layer.upload-chunk url=%s repository=%s range=%d-%d", session.URL, repository, start, end)
+
+	req, err := http.NewRequest("PATCH", session.URL.String(), strings.NewReader(string(chunk)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", start, end))
+	req.Header.Set("Content-Length", strconv.Itoa(len(chunk)))
+
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		acked, parseErr := parseRangeEnd(resp.Header.Get("Range"))
+		if parseErr != nil {
+			return fmt.Errorf("registry: chunk rejected with 416 and no usable Range header: %w", parseErr)
+		}
+		session.Offset = acked + 1
+		if location := resp.Header.Get("Location"); location != "" {
+			locationURL, err := url.Parse(location)
+			if err != nil {
+				return err
+			}
+			session.URL = locationURL
+		}
+		return fmt.Errorf("registry: chunk upload out of sync, registry has %d bytes, retry from there", session.Offset)
+	}
+
+	if err := updateSessionFromResponse(session, resp, end); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// finalizeUpload PUTs the computed digest to the upload session's URL with
+// a zero-length body to complete the upload.
+func (r *Registry) finalizeUpload(ctx context.Context, repository string, dgst digest.Digest, session *UploadSession) error {
+	q := session.URL.Query()
+	q.Set("digest", dgst.String())
+	session.URL.RawQuery = q.Encode()
+
+	r.Logf("registry.layer.finalize-upload url=%s repository=%s digest=%s", session.URL, repository, dgst)
+
+	req, err := http.NewRequest("PUT", session.URL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", "0")
+
+	_, err = r.Client.Do(req.WithContext(ctx))
+	return err
+}
+
+// updateSessionFromResponse advances session.URL and session.Offset from a
+// chunk PATCH response, preferring the registry's own Location and Range
+// headers and falling back to the range we just sent.
+func updateSessionFromResponse(session *UploadSession, resp *http.Response, sentEnd int64) error {
+	if location := resp.Header.Get("Location"); location != "" {
+		locationURL, err := url.Parse(location)
+		if err != nil {
+			return err
+		}
+		session.URL = locationURL
+	}
+
+	if rng := resp.Header.Get("Range"); rng != "" {
+		acked, err := parseRangeEnd(rng)
+		if err != nil {
+			return err
+		}
+		session.Offset = acked + 1
+		return nil
+	}
+
+	session.Offset = sentEnd + 1
+	return nil
+}
+
+// parseRangeEnd extracts the end offset from a "0-1023" style Range
+// response header.
+func parseRangeEnd(rng string) (int64, error) {
+	parts := strings.SplitN(rng, "-", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("registry: malformed Range header %q", rng)
+	}
+	return strconv.ParseInt(parts[1], 10, 64)
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// fakeChunkedUpload simulates a registry that accepts a chunked upload but
+// rejects the first PATCH with a 416, acknowledging only half the bytes,
+// so the test can exercise ResumeUpload.
+type fakeChunkedUpload struct {
+	patchCount int
+	ackedUpTo  int64
+	received   []byte
+	finalized  bool
+}
+
+func newFakeChunkedUploadServer(t *testing.T, fake *fakeChunkedUpload) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Fatalf("unexpected method %s on uploads endpoint", r.Method)
+		}
+		w.Header().Set("Location", fmt.Sprintf("http://%s/v2/test/blobs/uploads/session1", r.Host))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	sessionHandler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			fake.patchCount++
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("reading chunk body: %v", err)
+			}
+
+			if fake.patchCount == 1 {
+				half := int64(len(body) / 2)
+				fake.received = append(fake.received, body[:half]...)
+				fake.ackedUpTo = half - 1
+				w.Header().Set("Location", fmt.Sprintf("http://%s/v2/test/blobs/uploads/session2", r.Host))
+				w.Header().Set("Range", fmt.Sprintf("0-%d", fake.ackedUpTo))
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+
+			fake.received = append(fake.received, body...)
+			fake.ackedUpTo += int64(len(body))
+			w.Header().Set("Location", fmt.Sprintf("http://%s/v2/test/blobs/uploads/session2", r.Host))
+			w.Header().Set("Range", fmt.Sprintf("0-%d", fake.ackedUpTo))
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			fake.finalized = true
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Fatalf("unexpected method %s on session endpoint", r.Method)
+		}
+	}
+	mux.HandleFunc("/v2/test/blobs/uploads/session1", sessionHandler)
+	mux.HandleFunc("/v2/test/blobs/uploads/session2", sessionHandler)
+
+	return httptest.NewServer(mux)
+}
+
+func TestUploadLayerChunkedResumesAfter416(t *testing.T) {
+	fake := &fakeChunkedUpload{}
+	server := newFakeChunkedUploadServer(t, fake)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	content := strings.Repeat("a", 10)
+	dgst := digest.FromString(content)
+
+	session, err := r.UploadLayerChunked(context.Background(), "test", dgst, strings.NewReader(content), 10)
+	if err == nil {
+		t.Fatal("expected the mid-stream 416 to surface as an error")
+	}
+	if session == nil {
+		t.Fatal("expected a session to be returned alongside the error")
+	}
+	if want := fake.ackedUpTo + 1; session.Offset != want {
+		t.Fatalf("session.Offset = %d, want %d", session.Offset, want)
+	}
+	if want := fmt.Sprintf("%s/v2/test/blobs/uploads/session2", server.URL); session.URL.String() != want {
+		t.Fatalf("session.URL = %q, want %q (expected the 416's Location to be applied)", session.URL, want)
+	}
+
+	remaining := content[session.Offset:]
+	resumed, err := r.ResumeUpload(context.Background(), session.URL.String(), session.Offset, dgst, strings.NewReader(remaining))
+	if err != nil {
+		t.Fatalf("ResumeUpload: %v", err)
+	}
+	if !fake.finalized {
+		t.Fatal("expected the upload to be finalized after resuming")
+	}
+	if string(fake.received) != content {
+		t.Fatalf("registry received %q, want %q", fake.received, content)
+	}
+	if resumed.Offset != int64(len(content)) {
+		t.Fatalf("resumed.Offset = %d, want %d", resumed.Offset, len(content))
+	}
+}
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// UploadLayerStream uploads content whose digest isn't known upfront,
+// hashing it with sha256 as it's chunk-uploaded and finalizing with the
+// computed digest once content is exhausted.
+func (r *Registry) UploadLayerStream(ctx context.Context, repository string, content io.Reader) (digest.Digest, int64, error) {
+	uploadURL, err := r.initiateUpload(ctx, repository)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hasher := sha256.New()
+	counter := &countingReader{reader: io.TeeReader(content, hasher)}
+
+	session := &UploadSession{URL: uploadURL}
+	if err := r.uploadChunks(ctx, repository, session, counter, defaultChunkSize); err != nil {
+		return "", 0, err
+	}
+
+	dgst := digest.NewDigest(digest.SHA256, hasher)
+	if err := r.finalizeUpload(ctx, repository, dgst, session); err != nil {
+		return "", 0, err
+	}
+
+	return dgst, counter.n, nil
+}
+
+// countingReader wraps an io.Reader and tracks the total number of bytes
+// read, so UploadLayerStream can report the final blob size alongside its
+// computed digest.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
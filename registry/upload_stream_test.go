@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestUploadLayerStream(t *testing.T) {
+	const content = "streamed layer contents"
+	wantDigest := digest.FromString(content)
+
+	var received []byte
+	var finalizeDigest string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", fmt.Sprintf("http://%s/v2/test/blobs/uploads/session1", r.Host))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/v2/test/blobs/uploads/session1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("reading chunk body: %v", err)
+			}
+			received = append(received, body...)
+			w.Header().Set("Range", fmt.Sprintf("0-%d", len(received)-1))
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			finalizeDigest = r.URL.Query().Get("digest")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	dgst, size, err := r.UploadLayerStream(context.Background(), "test", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadLayerStream: %v", err)
+	}
+	if dgst != wantDigest {
+		t.Errorf("digest = %q, want %q", dgst, wantDigest)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("size = %d, want %d", size, len(content))
+	}
+	if string(received) != content {
+		t.Errorf("registry received %q, want %q", received, content)
+	}
+	if finalizeDigest != wantDigest.String() {
+		t.Errorf("finalize digest = %q, want %q", finalizeDigest, wantDigest)
+	}
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// DownloadLayerVerified downloads a layer like DownloadLayer, but verifies
+// the integrity of the response. It first checks the server's
+// Docker-Content-Digest header against the requested digest, then returns
+// a reader that hashes bytes as they are read; once the body is fully
+// consumed (on EOF or Close), Read/Close return an error if the computed
+// digest does not match the requested one.
+func (r *Registry) DownloadLayerVerified(ctx context.Context, repository string, dgst digest.Digest) (io.ReadCloser, error) {
+	url := r.url("/v2/%s/blobs/%s", repository, dgst)
+	r.Logf("registry.layer.download-verified url=%s repository=%s digest=%s", url, repository, dgst)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if serverDigest := resp.Header.Get("Docker-Content-Digest"); serverDigest != "" && serverDigest != dgst.String() {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry: Docker-Content-Digest %s does not match requested digest %s", serverDigest, dgst)
+	}
+
+	return &digestVerifyingReader{
+		body:     resp.Body,
+		digest:   dgst,
+		verifier: dgst.Verifier(),
+	}, nil
+}
+
+// digestVerifyingReader wraps a blob response body, feeding every byte read
+// through the requested digest's hash and failing Read/Close once the body
+// is exhausted if the computed digest doesn't match.
+type digestVerifyingReader struct {
+	body     io.ReadCloser
+	digest   digest.Digest
+	verifier digest.Verifier
+	checked  bool
+}
+
+func (d *digestVerifyingReader) Read(p []byte) (int, error) {
+	n, err := d.body.Read(p)
+	if n > 0 {
+		if _, werr := d.verifier.Write(p[:n]); werr != nil {
+			return n, werr
+		}
+	}
+	if err == io.EOF {
+		if verr := d.verify(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
+func (d *digestVerifyingReader) Close() error {
+	if err := d.body.Close(); err != nil {
+		return err
+	}
+	return d.verify()
+}
+
+func (d *digestVerifyingReader) verify() error {
+	if d.checked {
+		return nil
+	}
+	d.checked = true
+	if !d.verifier.Verified() {
+		return fmt.Errorf("registry: downloaded content does not match digest %s", d.digest)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestDownloadLayerVerified(t *testing.T) {
+	const content = "layer contents"
+	dgst := digest.FromString(content)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/"+dgst.String(), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Write([]byte(content))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	body, err := r.DownloadLayerVerified(context.Background(), "test", dgst)
+	if err != nil {
+		t.Fatalf("DownloadLayerVerified: %v", err)
+	}
+	defer body.Close()
+
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if string(got) != content {
+		t.Fatalf("body = %q, want %q", got, content)
+	}
+	if err := body.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestDownloadLayerVerifiedRejectsMismatchedContent(t *testing.T) {
+	dgst := digest.FromString("layer contents")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/blobs/"+dgst.String(), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Write([]byte("not the right content"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	r := &Registry{URL: server.URL, Client: server.Client(), Logf: Quiet}
+
+	body, err := r.DownloadLayerVerified(context.Background(), "test", dgst)
+	if err != nil {
+		t.Fatalf("DownloadLayerVerified: %v", err)
+	}
+	defer body.Close()
+
+	if _, err := io.ReadAll(body); err == nil {
+		t.Fatal("expected reading the body to surface a digest mismatch error")
+	}
+}